@@ -0,0 +1,200 @@
+package doccache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sebastianmontero/hypha-document-cache-go/dgraph"
+)
+
+// batchEdgeOp records a pending MutateEdge call queued on a Batch.
+type batchEdgeOp struct {
+	edge     *ChainEdge
+	deleteOp bool
+}
+
+//Batch accumulates chain document upserts, deletes and edge mutations so
+//they can be applied to Dgraph as a single transaction, instead of one
+//round trip per document/edge. Obtain one with Doccache.NewBatch and
+//either Commit or Discard it once all the operations for a block have
+//been queued.
+type Batch struct {
+	doccache  *Doccache
+	stores    []*ChainDocument
+	deletes   []*ChainDocument
+	edges     []*batchEdgeOp
+	newFields map[string]bool
+	txn       *dgraph.Txn
+	discarded bool
+}
+
+//NewBatch starts a new Batch backed by a fresh Dgraph transaction
+func (m *Doccache) NewBatch(ctx context.Context) *Batch {
+	return &Batch{
+		doccache:  m,
+		newFields: make(map[string]bool),
+		txn:       m.dgraph.NewTxn(ctx),
+	}
+}
+
+//StoreDocument queues a document upsert to be applied on Commit
+func (b *Batch) StoreDocument(chainDoc *ChainDocument) {
+	b.stores = append(b.stores, chainDoc)
+}
+
+//DeleteDocument queues a document delete to be applied on Commit
+func (b *Batch) DeleteDocument(chainDoc *ChainDocument) {
+	b.deletes = append(b.deletes, chainDoc)
+}
+
+//MutateEdge queues an edge create/delete to be applied on Commit. Any new
+//edge name is coalesced into a single Document schema alter at Commit time,
+//rather than one alter per call as Doccache.MutateEdge does.
+func (b *Batch) MutateEdge(chainEdge *ChainEdge, deleteOp bool) {
+	b.edges = append(b.edges, &batchEdgeOp{edge: chainEdge, deleteOp: deleteOp})
+	if _, ok := b.doccache.documentFieldMap[chainEdge.Name]; !ok {
+		b.newFields[chainEdge.Name] = true
+	}
+}
+
+//Commit resolves every hash referenced by the batch in a single
+//GetHashUIDMap lookup, applies the queued schema alter (if any new edge
+//names were seen), stages all the upserts, deletes and edge mutations
+//within the batch's transaction, and commits it. Cache updates and
+//listener notifications are only applied once that commit succeeds, so a
+//failed Commit never leaves the cache or listeners believing a mutation
+//happened that Dgraph never actually persisted.
+func (b *Batch) Commit(ctx context.Context) error {
+	if b.discarded {
+		return fmt.Errorf("batch already discarded")
+	}
+	if len(b.newFields) > 0 {
+		fields := make([]string, 0, len(b.newFields))
+		for field := range b.newFields {
+			fields = append(fields, field)
+		}
+		if err := b.doccache.updateDocumentTypeSchemas(fields); err != nil {
+			return err
+		}
+	}
+
+	prebuilt := b.prebuildNewDocuments()
+	hashes := b.referencedHashes(prebuilt)
+	hashUIDMap, err := b.doccache.GetHashUIDMap(hashes)
+	if err != nil {
+		return err
+	}
+
+	var effects []func()
+
+	for _, chainDoc := range b.stores {
+		doc, err := b.doccache.prepareStoreWithPrebuilt(chainDoc, hashUIDMap, prebuilt[chainDoc.Hash])
+		if err != nil {
+			return err
+		}
+		if _, err := b.txn.MutateJSON(doc, false); err != nil {
+			return err
+		}
+		effects = append(effects, func() {
+			//a newly created document has no UID on doc, so only an
+			//existing one is cached
+			if doc.UID != "" {
+				b.doccache.cache.PutDocument(doc)
+			}
+			b.doccache.notifyDocumentStored(doc)
+		})
+	}
+
+	for _, chainDoc := range b.deletes {
+		uid, ok := hashUIDMap[chainDoc.Hash]
+		if !ok {
+			continue
+		}
+		if _, err := b.txn.DeleteNode(uid); err != nil {
+			return err
+		}
+		hash := chainDoc.Hash
+		effects = append(effects, func() {
+			b.doccache.cache.RemoveDocument(hash)
+			b.doccache.notifyDocumentDeleted(hash, uid)
+		})
+	}
+
+	for _, op := range b.edges {
+		fromUID, ok := hashUIDMap[op.edge.From]
+		if !ok {
+			return fmt.Errorf("From node of the relationship: [Edge: %v, From: %v, To: %v] does not exist, Delete Op: %v", op.edge.Name, op.edge.From, op.edge.To, op.deleteOp)
+		}
+		toUID, ok := hashUIDMap[op.edge.To]
+		if !ok {
+			return fmt.Errorf("To node of the relationship: [Edge: %v, From: %v, To: %v] does not exist, Delete Op: %v", op.edge.Name, op.edge.From, op.edge.To, op.deleteOp)
+		}
+		if _, err := b.txn.MutateEdge(fromUID, toUID, op.edge.Name, op.deleteOp); err != nil {
+			return err
+		}
+		op := op
+		effects = append(effects, func() {
+			b.doccache.cache.AddEdge(op.edge.From, op.edge.Name, op.deleteOp)
+			b.doccache.cache.InvalidateDocument(op.edge.From)
+			b.doccache.notifyEdgeMutated(op.edge, fromUID, toUID, op.deleteOp)
+		})
+	}
+
+	if err := b.txn.Commit(ctx); err != nil {
+		return err
+	}
+	for _, effect := range effects {
+		effect()
+	}
+	return nil
+}
+
+//Discard abandons the batch's transaction without applying any of its
+//queued operations. Safe to call after a failed Commit.
+func (b *Batch) Discard(ctx context.Context) error {
+	b.discarded = true
+	return b.txn.Discard(ctx)
+}
+
+//prebuildNewDocuments transforms every queued store into a Document up
+//front, keyed by its chain hash, so referencedHashes can fold each one's
+//checksum256 content references into the batch's single GetHashUIDMap
+//lookup. A document that turns out to already exist (hashUIDMap hit in
+//Commit) simply has its prebuilt transform discarded in favor of updating
+//its certificates instead.
+func (b *Batch) prebuildNewDocuments() map[string]*newDocumentContent {
+	prebuilt := make(map[string]*newDocumentContent, len(b.stores))
+	for _, chainDoc := range b.stores {
+		prebuilt[chainDoc.Hash] = b.doccache.transformNewUnresolved(chainDoc)
+	}
+	return prebuilt
+}
+
+//referencedHashes collects every hash this batch needs a UID for: the
+//stored/deleted documents themselves, the checksum256 content references
+//each prebuilt new document carries, and the From/To hashes of every
+//queued edge.
+func (b *Batch) referencedHashes(prebuilt map[string]*newDocumentContent) []string {
+	seen := make(map[string]bool)
+	hashes := make([]string, 0)
+	add := func(hash string) {
+		if hash != "" && !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+	for _, chainDoc := range b.stores {
+		add(chainDoc.Hash)
+		for _, checksumContent := range prebuilt[chainDoc.Hash].checksumContents {
+			add(checksumContent.Value)
+		}
+	}
+	for _, chainDoc := range b.deletes {
+		add(chainDoc.Hash)
+	}
+	for _, op := range b.edges {
+		add(op.edge.From)
+		add(op.edge.To)
+	}
+	return hashes
+}
@@ -0,0 +1,252 @@
+package doccache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultDocumentCacheSize is the number of full Documents kept in memory
+// when a Doccache is created with New.
+const DefaultDocumentCacheSize = 1000
+
+// DefaultExcerptCacheSize is the number of Excerpts kept in memory when a
+// Doccache is created with New. Excerpts are much cheaper than full
+// Documents, so this defaults considerably higher.
+const DefaultExcerptCacheSize = 20000
+
+// Excerpt is a lightweight projection of a Document, holding just the
+// fields needed to resolve hash/UID lookups and checksum content
+// references without fetching the full document from Dgraph.
+type Excerpt struct {
+	Hash        string
+	UID         string
+	Creator     string
+	CreatedDate string
+	Type        string
+	Edges       []string
+}
+
+// NewExcerpt builds the lightweight projection of doc that is kept in the
+// excerpt cache.
+func NewExcerpt(doc *Document) *Excerpt {
+	if doc == nil {
+		return nil
+	}
+	return &Excerpt{
+		Hash:        doc.Hash,
+		UID:         doc.UID,
+		Creator:     doc.Creator,
+		CreatedDate: doc.CreatedDate,
+		Type:        doc.GetType(),
+	}
+}
+
+// CacheMetrics exposes hit/miss/eviction counters for the document and
+// excerpt caches, so operators can tune CacheConfig sizes.
+type CacheMetrics struct {
+	DocumentHits      uint64
+	DocumentMisses    uint64
+	DocumentEvictions uint64
+	ExcerptHits       uint64
+	ExcerptMisses     uint64
+	ExcerptEvictions  uint64
+}
+
+// CacheConfig configures the sizes of the two cache tiers kept in front of
+// Dgraph by a Doccache.
+type CacheConfig struct {
+	DocumentCacheSize int
+	ExcerptCacheSize  int
+}
+
+// DefaultCacheConfig returns the CacheConfig used by New.
+func DefaultCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		DocumentCacheSize: DefaultDocumentCacheSize,
+		ExcerptCacheSize:  DefaultExcerptCacheSize,
+	}
+}
+
+// cache is the two tier LRU sitting in front of Dgraph: a bounded cache of
+// full Documents keyed by hash, and a larger cache of lightweight Excerpts,
+// also keyed by hash. It is safe for concurrent use.
+type cache struct {
+	mutex     sync.Mutex
+	documents *lru
+	excerpts  *lru
+	metrics   CacheMetrics
+}
+
+func newCache(cfg *CacheConfig) *cache {
+	if cfg == nil {
+		cfg = DefaultCacheConfig()
+	}
+	return &cache{
+		documents: newLRU(cfg.DocumentCacheSize),
+		excerpts:  newLRU(cfg.ExcerptCacheSize),
+	}
+}
+
+func (c *cache) GetDocument(hash string) (*Document, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	v, ok := c.documents.Get(hash)
+	if !ok {
+		c.metrics.DocumentMisses++
+		return nil, false
+	}
+	c.metrics.DocumentHits++
+	return v.(*Document), true
+}
+
+func (c *cache) PutDocument(doc *Document) {
+	if doc == nil || doc.Hash == "" {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.documents.Put(doc.Hash, doc) {
+		c.metrics.DocumentEvictions++
+	}
+	c.putExcerptLocked(NewExcerpt(doc))
+}
+
+func (c *cache) RemoveDocument(hash string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.documents.Remove(hash)
+	c.excerpts.Remove(hash)
+}
+
+// InvalidateDocument drops hash from the full-document cache only, leaving
+// its excerpt (and any edges recorded on it) intact.
+func (c *cache) InvalidateDocument(hash string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.documents.Remove(hash)
+}
+
+func (c *cache) GetExcerpt(hash string) (*Excerpt, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	v, ok := c.excerpts.Get(hash)
+	if !ok {
+		c.metrics.ExcerptMisses++
+		return nil, false
+	}
+	c.metrics.ExcerptHits++
+	return v.(*Excerpt), true
+}
+
+func (c *cache) PutExcerpt(excerpt *Excerpt) {
+	if excerpt == nil || excerpt.Hash == "" {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.putExcerptLocked(excerpt)
+}
+
+func (c *cache) putExcerptLocked(excerpt *Excerpt) {
+	if excerpt == nil || excerpt.Hash == "" {
+		return
+	}
+	if c.excerpts.Put(excerpt.Hash, excerpt) {
+		c.metrics.ExcerptEvictions++
+	}
+}
+
+// AddEdge updates a cached excerpt, if present, to record a newly
+// created/deleted edge so GetHashUIDMap-driven traversals stay accurate
+// without a round trip to Dgraph.
+func (c *cache) AddEdge(hash, edgeName string, deleted bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	v, ok := c.excerpts.Get(hash)
+	if !ok {
+		return
+	}
+	excerpt := v.(*Excerpt)
+	if deleted {
+		for i, name := range excerpt.Edges {
+			if name == edgeName {
+				excerpt.Edges = append(excerpt.Edges[:i], excerpt.Edges[i+1:]...)
+				break
+			}
+		}
+		return
+	}
+	for _, name := range excerpt.Edges {
+		if name == edgeName {
+			return
+		}
+	}
+	excerpt.Edges = append(excerpt.Edges, edgeName)
+}
+
+func (c *cache) Metrics() CacheMetrics {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.metrics
+}
+
+// lru is a minimal, unexported fixed-size least-recently-used cache
+// mapping string keys to arbitrary values.
+type lru struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+func newLRU(capacity int) *lru {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &lru{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+func (l *lru) Get(key string) (interface{}, bool) {
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+// Put inserts or updates key and reports whether an existing entry had to
+// be evicted to make room for it.
+func (l *lru) Put(key string, value interface{}) bool {
+	if elem, ok := l.entries[key]; ok {
+		elem.Value.(*lruEntry).value = value
+		l.order.MoveToFront(elem)
+		return false
+	}
+	elem := l.order.PushFront(&lruEntry{key: key, value: value})
+	l.entries[key] = elem
+	if l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		if oldest != nil {
+			l.order.Remove(oldest)
+			delete(l.entries, oldest.Value.(*lruEntry).key)
+			return true
+		}
+	}
+	return false
+}
+
+func (l *lru) Remove(key string) {
+	if elem, ok := l.entries[key]; ok {
+		l.order.Remove(elem)
+		delete(l.entries, key)
+	}
+}
@@ -0,0 +1,99 @@
+package doccache
+
+import "testing"
+
+func TestLRUEvictsOldest(t *testing.T) {
+	l := newLRU(2)
+
+	if evicted := l.Put("a", 1); evicted {
+		t.Fatalf("Put(a) reported an eviction with room to spare")
+	}
+	if evicted := l.Put("b", 2); evicted {
+		t.Fatalf("Put(b) reported an eviction with room to spare")
+	}
+
+	// touch "a" so "b" becomes the least recently used entry
+	if _, ok := l.Get("a"); !ok {
+		t.Fatalf("expected a to be present")
+	}
+
+	if evicted := l.Put("c", 3); !evicted {
+		t.Fatalf("Put(c) should have evicted the least recently used entry")
+	}
+	if _, ok := l.Get("b"); ok {
+		t.Fatalf("expected b to have been evicted, got a hit")
+	}
+	if _, ok := l.Get("a"); !ok {
+		t.Fatalf("expected a to still be cached")
+	}
+	if _, ok := l.Get("c"); !ok {
+		t.Fatalf("expected c to be cached")
+	}
+}
+
+func TestCacheDocumentHitMissEvictionMetrics(t *testing.T) {
+	c := newCache(&CacheConfig{DocumentCacheSize: 1, ExcerptCacheSize: 1})
+
+	if _, ok := c.GetDocument("h1"); ok {
+		t.Fatalf("expected miss on empty cache")
+	}
+
+	c.PutDocument(&Document{Hash: "h1"})
+	c.PutDocument(&Document{Hash: "h2"})
+
+	if _, ok := c.GetDocument("h1"); ok {
+		t.Fatalf("expected h1 to have been evicted to make room for h2")
+	}
+	if _, ok := c.GetDocument("h2"); !ok {
+		t.Fatalf("expected h2 to be cached")
+	}
+
+	metrics := c.Metrics()
+	// one miss on the empty cache, one for the evicted h1
+	if metrics.DocumentMisses != 2 {
+		t.Errorf("DocumentMisses = %v, want 2", metrics.DocumentMisses)
+	}
+	if metrics.DocumentHits != 1 {
+		t.Errorf("DocumentHits = %v, want 1", metrics.DocumentHits)
+	}
+	if metrics.DocumentEvictions != 1 {
+		t.Errorf("DocumentEvictions = %v, want 1", metrics.DocumentEvictions)
+	}
+}
+
+func TestCacheInvalidateDocumentKeepsExcerpt(t *testing.T) {
+	c := newCache(DefaultCacheConfig())
+	c.PutDocument(&Document{Hash: "h1", Creator: "alice"})
+	c.AddEdge("h1", "comments", false)
+
+	c.InvalidateDocument("h1")
+
+	if _, ok := c.GetDocument("h1"); ok {
+		t.Fatalf("expected full document to be dropped")
+	}
+	excerpt, ok := c.GetExcerpt("h1")
+	if !ok {
+		t.Fatalf("expected excerpt to survive InvalidateDocument")
+	}
+	if len(excerpt.Edges) != 1 || excerpt.Edges[0] != "comments" {
+		t.Errorf("excerpt.Edges = %v, want [comments]", excerpt.Edges)
+	}
+}
+
+func TestCacheAddEdgeAddsAndRemoves(t *testing.T) {
+	c := newCache(DefaultCacheConfig())
+	c.PutExcerpt(&Excerpt{Hash: "h1"})
+
+	c.AddEdge("h1", "comments", false)
+	c.AddEdge("h1", "comments", false)
+	excerpt, _ := c.GetExcerpt("h1")
+	if len(excerpt.Edges) != 1 {
+		t.Fatalf("expected duplicate AddEdge to be a no-op, got %v", excerpt.Edges)
+	}
+
+	c.AddEdge("h1", "comments", true)
+	excerpt, _ = c.GetExcerpt("h1")
+	if len(excerpt.Edges) != 0 {
+		t.Errorf("expected AddEdge(deleted=true) to remove the edge, got %v", excerpt.Edges)
+	}
+}
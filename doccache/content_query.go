@@ -0,0 +1,196 @@
+package doccache
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ContentFilter selects documents by creator, by a content label/value/type
+//triple, or by a range/full-text condition on a content's typed value
+//(populateTypedValues fills in value_int/value_time/value_asset_*/
+//value_fulltext when a content's type supports it), with simple
+//offset-based pagination. It backs Doccache.QueryContents, which the
+//graphql package uses to answer "documents" queries.
+type ContentFilter struct {
+	Creator string
+	Label   string
+	Value   string
+	Type    string
+
+	//IntMin/IntMax bound value_int; either may be left nil for an
+	//open-ended range.
+	IntMin *int64
+	IntMax *int64
+	//TimeAfter/TimeBefore bound value_time.
+	TimeAfter  *time.Time
+	TimeBefore *time.Time
+	//AssetAmountMin/AssetAmountMax bound value_asset_amount; AssetSymbol,
+	//if set, additionally requires an exact value_asset_symbol match.
+	AssetAmountMin *float64
+	AssetAmountMax *float64
+	AssetSymbol    string
+	//Fulltext runs an alloftext query against value_fulltext.
+	Fulltext string
+
+	//After is an opaque cursor previously returned as part of a result;
+	//pass "" to start from the beginning.
+	After string
+	//First caps the number of hashes returned; 0 means DefaultPageSize.
+	First int
+}
+
+//DefaultPageSize is used by QueryContents when filter.First is 0.
+const DefaultPageSize = 20
+
+//EncodeCursor turns an offset into the opaque cursor string QueryContents
+//accepts as ContentFilter.After.
+func EncodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursorOffset(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("doccache: invalid cursor %q: %v", cursor, err)
+	}
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil {
+		return 0, fmt.Errorf("doccache: invalid cursor %q: %v", cursor, err)
+	}
+	return offset, nil
+}
+
+//QueryContents finds the hashes of documents matching filter. A creator
+//filter runs directly against the Document's own index; every other
+//filter runs against Content (its term/exact/range/fulltext indexes) and
+//walks the contents/content_groups @reverse edges back up to the owning
+//Document. It returns the requested page of hashes along with whether a
+//further page is available.
+func (m *Doccache) QueryContents(filter ContentFilter) (hashes []string, hasNext bool, err error) {
+	offset, err := decodeCursorOffset(filter.After)
+	if err != nil {
+		return nil, false, err
+	}
+	first := filter.First
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+
+	query, ok := contentFilterQuery(filter, first+1, offset)
+	if !ok {
+		return nil, false, nil
+	}
+
+	docs := &Docs{}
+	if err := m.dgraph.Query(query, nil, docs); err != nil {
+		return nil, false, err
+	}
+
+	hasNext = len(docs.Docs) > first
+	if hasNext {
+		docs.Docs = docs.Docs[:first]
+	}
+	hashes = make([]string, 0, len(docs.Docs))
+	for _, doc := range docs.Docs {
+		hashes = append(hashes, doc.Hash)
+	}
+	return hashes, hasNext, nil
+}
+
+//contentFilterQuery builds the Dgraph query matching filter, preferring
+//the Document-level creator index when present. It reports false if
+//filter has no usable predicate.
+func contentFilterQuery(filter ContentFilter, first, offset int) (string, bool) {
+	if filter.Creator != "" {
+		return fmt.Sprintf(`
+			{
+				docs(func: eq(creator, %v), first: %v, offset: %v){
+					hash
+				}
+			}
+		`, quote(filter.Creator), first, offset), true
+	}
+
+	conditions := contentConditions(filter)
+	if len(conditions) == 0 {
+		return "", false
+	}
+	root := conditions[0]
+	extraFilter := ""
+	if len(conditions) > 1 {
+		extraFilter = fmt.Sprintf("@filter(%v)", strings.Join(conditions[1:], " AND "))
+	}
+	return fmt.Sprintf(`
+		{
+			matches(func: %v) %v{
+				groups as ~contents
+			}
+			docUIDs(func: uid(groups)){
+				docs as ~content_groups
+			}
+			docs(func: uid(docs), first: %v, offset: %v){
+				hash
+			}
+		}
+	`, root, extraFilter, first, offset), true
+}
+
+//contentConditions builds the list of Content-level index conditions
+//implied by filter's non-creator fields, most selective (equality) first
+//so it can be used as the Dgraph root func with the rest applied via
+//@filter.
+func contentConditions(filter ContentFilter) []string {
+	var conditions []string
+	add := func(cond string) { conditions = append(conditions, cond) }
+
+	if filter.Label != "" {
+		add(fmt.Sprintf("eq(label, %v)", quote(filter.Label)))
+	}
+	if filter.Value != "" {
+		add(fmt.Sprintf("eq(value, %v)", quote(filter.Value)))
+	}
+	if filter.Type != "" {
+		add(fmt.Sprintf("eq(type, %v)", quote(filter.Type)))
+	}
+	if filter.AssetSymbol != "" {
+		add(fmt.Sprintf("eq(value_asset_symbol, %v)", quote(filter.AssetSymbol)))
+	}
+	if filter.Fulltext != "" {
+		add(fmt.Sprintf("alloftext(value_fulltext, %v)", quote(filter.Fulltext)))
+	}
+	if filter.IntMin != nil {
+		add(fmt.Sprintf("ge(value_int, %v)", *filter.IntMin))
+	}
+	if filter.IntMax != nil {
+		add(fmt.Sprintf("le(value_int, %v)", *filter.IntMax))
+	}
+	if filter.TimeAfter != nil {
+		add(fmt.Sprintf("ge(value_time, %v)", quote(filter.TimeAfter.Format(time.RFC3339))))
+	}
+	if filter.TimeBefore != nil {
+		add(fmt.Sprintf("le(value_time, %v)", quote(filter.TimeBefore.Format(time.RFC3339))))
+	}
+	if filter.AssetAmountMin != nil {
+		add(fmt.Sprintf("ge(value_asset_amount, %v)", *filter.AssetAmountMin))
+	}
+	if filter.AssetAmountMax != nil {
+		add(fmt.Sprintf("le(value_asset_amount, %v)", *filter.AssetAmountMax))
+	}
+	return conditions
+}
+
+//quote escapes s for use as a DQL string literal. Backslashes must be
+//escaped before quotes, or a value ending in a backslash (e.g. from a
+//GraphQL client's contentValue arg) would escape the closing quote and
+//let the rest of s run into the query as DQL rather than a string.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
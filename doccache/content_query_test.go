@@ -0,0 +1,19 @@
+package doccache
+
+import "testing"
+
+func TestQuoteEscapesBackslashesBeforeQuotes(t *testing.T) {
+	got := quote(`C:\temp\"; drop everything`)
+	want := `"C:\\temp\\\"; drop everything"`
+	if got != want {
+		t.Errorf("quote(...) = %v, want %v", got, want)
+	}
+}
+
+func TestQuoteTrailingBackslashDoesNotEscapeClosingQuote(t *testing.T) {
+	got := quote(`value\`)
+	want := `"value\\"`
+	if got != want {
+		t.Errorf("quote(...) = %v, want %v; a trailing backslash must not escape the closing quote", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package doccache
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Chain content types known to carry a value worth indexing beyond the
+//plain string term index. These match the type tags EOSIO chain actions
+//use for document content (see transformNew).
+const (
+	contentTypeInt          = "int64"
+	contentTypeTimePoint    = "time_point"
+	contentTypeAsset        = "asset"
+	contentTypeString       = "string"
+	contentTypeName         = "name"
+	contentTypeChecksum256  = "checksum256"
+)
+
+//fulltextMinLength is the shortest string value worth writing to
+//value_fulltext; shorter values are already well served by the term
+//index on value.
+const fulltextMinLength = 64
+
+//eosioTimePointLayout is the zone-less ISO-8601 format EOSIO chain actions
+//actually send for a time_point content, e.g. "2020-05-13T12:00:00.000".
+//time.RFC3339 requires a timezone designator these values don't carry, so
+//parsing with it alone silently fails for every real time_point value.
+const eosioTimePointLayout = "2006-01-02T15:04:05.999"
+
+//populateTypedValues walks doc's content groups and, for each content
+//whose declared type carries a parseable value, fills in the matching
+//typed predicate (value_int, value_time, value_asset_amount/symbol,
+//value_fulltext) alongside the existing string value. Unparseable values
+//are left with only their string form; they still work with the
+//existing term index, they just won't show up in typed range queries.
+func populateTypedValues(doc *Document) {
+	if doc == nil {
+		return
+	}
+	for _, group := range doc.ContentGroups {
+		for _, content := range group.Contents {
+			applyTypedValue(content)
+		}
+	}
+}
+
+func applyTypedValue(content *Content) {
+	switch content.Type {
+	case contentTypeInt:
+		if v, err := strconv.ParseInt(content.Value, 10, 64); err == nil {
+			content.ValueInt = &v
+		}
+	case contentTypeTimePoint:
+		if t, err := time.Parse(eosioTimePointLayout, content.Value); err == nil {
+			content.ValueTime = &t
+		} else if t, err := time.Parse(time.RFC3339, content.Value); err == nil {
+			content.ValueTime = &t
+		}
+	case contentTypeAsset:
+		if amount, symbol, ok := parseAsset(content.Value); ok {
+			content.ValueAssetAmount = &amount
+			content.ValueAssetSymbol = symbol
+		}
+	case contentTypeString, contentTypeName, contentTypeChecksum256:
+		if len(content.Value) >= fulltextMinLength {
+			content.ValueFulltext = content.Value
+		}
+	}
+}
+
+//parseAsset splits an EOSIO asset string, e.g. "1.0000 HYPHA", into its
+//numeric amount and symbol code.
+func parseAsset(value string) (amount float64, symbol string, ok bool) {
+	parts := strings.Fields(value)
+	if len(parts) != 2 {
+		return 0, "", false
+	}
+	amount, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return amount, parts[1], true
+}
@@ -0,0 +1,80 @@
+package doccache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAsset(t *testing.T) {
+	amount, symbol, ok := parseAsset("1.0000 HYPHA")
+	if !ok {
+		t.Fatalf("parseAsset returned ok=false for a valid asset")
+	}
+	if amount != 1.0 {
+		t.Errorf("amount = %v, want 1.0", amount)
+	}
+	if symbol != "HYPHA" {
+		t.Errorf("symbol = %v, want HYPHA", symbol)
+	}
+
+	if _, _, ok := parseAsset("not an asset value"); ok {
+		t.Errorf("expected parseAsset to reject a malformed value")
+	}
+}
+
+func TestApplyTypedValueInt(t *testing.T) {
+	content := &Content{Type: contentTypeInt, Value: "42"}
+	applyTypedValue(content)
+	if content.ValueInt == nil || *content.ValueInt != 42 {
+		t.Errorf("ValueInt = %v, want 42", content.ValueInt)
+	}
+}
+
+func TestApplyTypedValueTimePointZoneless(t *testing.T) {
+	content := &Content{Type: contentTypeTimePoint, Value: "2020-05-13T12:00:00.000"}
+	applyTypedValue(content)
+	if content.ValueTime == nil {
+		t.Fatalf("expected ValueTime to be populated from a zone-less EOSIO time_point value")
+	}
+	want := time.Date(2020, 5, 13, 12, 0, 0, 0, time.UTC)
+	if !content.ValueTime.Equal(want) {
+		t.Errorf("ValueTime = %v, want %v", content.ValueTime, want)
+	}
+}
+
+func TestApplyTypedValueTimePointRFC3339(t *testing.T) {
+	content := &Content{Type: contentTypeTimePoint, Value: "2020-05-13T12:00:00Z"}
+	applyTypedValue(content)
+	if content.ValueTime == nil {
+		t.Errorf("expected ValueTime to be populated from an RFC3339 time_point value")
+	}
+}
+
+func TestApplyTypedValueAsset(t *testing.T) {
+	content := &Content{Type: contentTypeAsset, Value: "2.5000 HYPHA"}
+	applyTypedValue(content)
+	if content.ValueAssetAmount == nil || *content.ValueAssetAmount != 2.5 {
+		t.Errorf("ValueAssetAmount = %v, want 2.5", content.ValueAssetAmount)
+	}
+	if content.ValueAssetSymbol != "HYPHA" {
+		t.Errorf("ValueAssetSymbol = %v, want HYPHA", content.ValueAssetSymbol)
+	}
+}
+
+func TestApplyTypedValueFulltext(t *testing.T) {
+	long := make([]byte, fulltextMinLength)
+	for i := range long {
+		long[i] = 'a'
+	}
+	content := &Content{Type: contentTypeString, Value: string(long)}
+	applyTypedValue(content)
+	if content.ValueFulltext != content.Value {
+		t.Errorf("expected ValueFulltext to be populated for a value at fulltextMinLength")
+	}
+
+	short := &Content{Type: contentTypeString, Value: "short"}
+	applyTypedValue(short)
+	if short.ValueFulltext != "" {
+		t.Errorf("expected ValueFulltext to stay empty below fulltextMinLength")
+	}
+}
@@ -1,13 +1,24 @@
 package doccache
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
 
 	"github.com/sebastianmontero/hypha-document-cache-go/dgraph"
+	"github.com/sebastianmontero/hypha-document-cache-go/doccache/migration"
 )
 
+//schema is the original, pre-migration base schema: the Document/
+//ContentGroup/Content/Certificate types and predicates a brand new Dgraph
+//instance needs. It intentionally stops there and must stay identical to
+//migration.Initial's own copy of it - the reverse edges added by
+//migration 0002 and the typed value_* predicates added by migration 0003
+//belong only in the migration package, not here, so there is exactly one
+//place that knows about each schema change after the original baseline.
+//PrepareSchema only takes a database this far; call Migrate afterwards to
+//reach the current schema.
 const schema = `
       type Document {
           hash
@@ -16,12 +27,12 @@ const schema = `
           content_groups
           certificates
       }
-      
+
       type ContentGroup {
         content_group_sequence
         contents
       }
-      
+
       type Content {
         label
         value
@@ -29,29 +40,29 @@ const schema = `
         content_sequence
         document
       }
-      
+
       type Certificate {
         certifier
         notes
         certification_date
         certification_sequence
       }
-      
+
       hash: string @index(exact) .
       created_date: datetime .
       creator: string @index(term) .
       content_groups: [uid] .
       certificates: [uid] .
-      
+
       content_group_sequence: int .
       contents: [uid] .
-      
+
       label: string @index(term) .
       value: string @index(term) .
       type: string @index(term) .
       content_sequence: int .
       document: [uid] .
-      
+
       certifier: string @index(term) .
       notes: string .
       certification_date: datetime .
@@ -89,20 +100,42 @@ type RequestConfig struct {
 	Edges         []string
 }
 
+//isFull reports whether rc requests everything needed for a Document to be
+//safely served out of the full-document cache
+func (rc *RequestConfig) isFull() bool {
+	return rc.ContentGroups && rc.Certificates && len(rc.Edges) == 0
+}
+
 //Doccache Service class to store and retrieve docs
 type Doccache struct {
 	dgraph           *dgraph.Dgraph
 	documentFieldMap map[string]*dgraph.SchemaField
+	cache            *cache
+	extraMigrations  []*migration.Migration
+	listeners        []Listener
 }
 
-//New creates a new doccache
+//New creates a new doccache, backed by a cache sized with DefaultCacheConfig
 func New(dg *dgraph.Dgraph) *Doccache {
+	return NewWithCacheConfig(dg, DefaultCacheConfig())
+}
+
+//NewWithCacheConfig creates a new doccache with a custom CacheConfig, allowing
+//callers to size the document/excerpt LRUs for their workload
+func NewWithCacheConfig(dg *dgraph.Dgraph, cacheCfg *CacheConfig) *Doccache {
 	return &Doccache{
 		dgraph:           dg,
 		documentFieldMap: make(map[string]*dgraph.SchemaField),
+		cache:            newCache(cacheCfg),
 	}
 }
 
+//CacheMetrics returns the current hit/miss/eviction counters for the
+//document and excerpt caches
+func (m *Doccache) CacheMetrics() CacheMetrics {
+	return m.cache.Metrics()
+}
+
 //SchemaExists set the base document schema in dgraph
 func (m *Doccache) SchemaExists() (bool, error) {
 	missing, err := m.dgraph.MissingTypes([]string{"Document", "ContentGroup", "Content", "Certificate"})
@@ -112,7 +145,10 @@ func (m *Doccache) SchemaExists() (bool, error) {
 	return len(missing) == 0, nil
 }
 
-//PrepareSchema prepares the base schema
+//PrepareSchema lays down the original base schema (see the schema
+//constant) on a brand new Dgraph instance. It predates the migration
+//package and only reaches the v1 shape; callers that need the reverse
+//edges or typed content indexes added since must call Migrate as well.
 func (m *Doccache) PrepareSchema() error {
 	exists, err := m.SchemaExists()
 	if err != nil {
@@ -128,8 +164,40 @@ func (m *Doccache) PrepareSchema() error {
 	return err
 }
 
+//Migrate applies any pending schema migrations to the underlying Dgraph
+//instance, using migration.DefaultBuilder plus any migrations registered
+//via RegisterMigrations, then refreshes the cached Document field map.
+//It refuses to run if the database's recorded schema version is newer
+//than the highest version this binary knows about.
+func (m *Doccache) Migrate(ctx context.Context) error {
+	builder := migration.DefaultBuilder()
+	for _, extra := range m.extraMigrations {
+		builder.Register(extra)
+	}
+	migrator := migration.NewMigrator(m.dgraph, builder.Build())
+	if err := migrator.Migrate(); err != nil {
+		return err
+	}
+	var err error
+	m.documentFieldMap, err = m.dgraph.GetTypeFieldMap("Document")
+	return err
+}
+
+//RegisterMigrations adds migrations on top of migration.DefaultBuilder for
+//the next call to Migrate, letting downstream users evolve the schema
+//(new indexes, predicate type changes, backfills, ...) without editing
+//the base schema constant.
+func (m *Doccache) RegisterMigrations(migrations ...*migration.Migration) {
+	m.extraMigrations = append(m.extraMigrations, migrations...)
+}
+
 //GetByHash Finds document by hash
 func (m *Doccache) GetByHash(hash string, rc *RequestConfig) (*Document, error) {
+	if rc.isFull() {
+		if doc, ok := m.cache.GetDocument(hash); ok {
+			return doc, nil
+		}
+	}
 	query := fmt.Sprintf(`
 		query docs($hash: string){
 			docs(func: eq(hash, $hash))
@@ -143,7 +211,11 @@ func (m *Doccache) GetByHash(hash string, rc *RequestConfig) (*Document, error)
 		return nil, err
 	}
 	if len(docs.Docs) > 0 {
-		return docs.Docs[0], nil
+		doc := docs.Docs[0]
+		if rc.isFull() {
+			m.cache.PutDocument(doc)
+		}
+		return doc, nil
 	}
 	return nil, nil
 }
@@ -177,6 +249,18 @@ func (m *Doccache) GetHashUIDMap(hashes []string) (map[string]string, error) {
 	if len(hashes) == 0 {
 		return make(map[string]string), nil
 	}
+	hashUIDMap := make(map[string]string, len(hashes))
+	missing := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		if excerpt, ok := m.cache.GetExcerpt(hash); ok {
+			hashUIDMap[hash] = excerpt.UID
+		} else {
+			missing = append(missing, hash)
+		}
+	}
+	if len(missing) == 0 {
+		return hashUIDMap, nil
+	}
 	query := fmt.Sprintf(`
 		{
 			docs(func: eq(hash, [%v])){
@@ -184,17 +268,18 @@ func (m *Doccache) GetHashUIDMap(hashes []string) (map[string]string, error) {
 				hash
 			}
 		}
-	`, strings.Join(hashes, ","))
+	`, strings.Join(missing, ","))
 
 	docs := &Docs{}
 	err := m.dgraph.Query(query, nil, docs)
 	if err != nil {
 		return nil, err
 	}
-	var hashUIDMap = make(map[string]string, len(hashes))
-
 	for _, doc := range docs.Docs {
 		hashUIDMap[doc.Hash] = doc.UID
+		if _, ok := m.cache.GetExcerpt(doc.Hash); !ok {
+			m.cache.PutExcerpt(&Excerpt{Hash: doc.Hash, UID: doc.UID})
+		}
 	}
 	return hashUIDMap, nil
 }
@@ -213,23 +298,95 @@ func (m *Doccache) GetUID(hash string) (string, error) {
 
 //StoreDocument Creates a new document or updates its certificates
 func (m *Doccache) StoreDocument(chainDoc *ChainDocument) error {
-	doc, err := m.GetByHash(chainDoc.Hash, &RequestConfig{Certificates: true})
+	doc, err := m.getExistingDocument(chainDoc.Hash)
+	if err != nil {
+		return err
+	}
+	doc, err = m.applyStore(chainDoc, doc)
 	if err != nil {
 		return err
 	}
-	if doc == nil {
+
+	_, err = m.dgraph.MutateJSON(doc, false)
+	if err != nil {
+		return err
+	}
+	//a newly created document has no UID on doc, so only an existing one
+	//is cached; caching it would resolve its hash to an empty UID
+	if doc.UID != "" {
+		m.cache.PutDocument(doc)
+	}
+	m.notifyDocumentStored(doc)
+	return nil
+}
+
+//getExistingDocument returns hash's full Document - content groups and
+//certificates included - from the document cache if present, otherwise
+//from Dgraph. Callers that go on to cache.PutDocument the result (directly
+//or via applyStore's certificate refresh) must start from a genuinely
+//complete Document; a partial fetch (e.g. certificates only) would poison
+//the full-document cache with a copy missing its content groups.
+func (m *Doccache) getExistingDocument(hash string) (*Document, error) {
+	if doc, ok := m.cache.GetDocument(hash); ok {
+		return doc, nil
+	}
+	return m.GetByHash(hash, &RequestConfig{ContentGroups: true, Certificates: true})
+}
+
+//applyStore turns chainDoc into the Document that should be written to
+//Dgraph: a brand new node via transformNew if existingDoc is nil, or a
+//copy of existingDoc with its certificates refreshed otherwise. existingDoc
+//itself, which may be the cached copy, is left untouched. Shared by
+//StoreDocument and Batch.Commit.
+func (m *Doccache) applyStore(chainDoc *ChainDocument, existingDoc *Document) (*Document, error) {
+	if existingDoc == nil {
 		log.Printf("Creating document: %v", chainDoc.Hash)
-		doc, err = m.transformNew(chainDoc)
+		return m.transformNew(chainDoc)
+	}
+	log.Printf("Updating certificates for document: <%v>%v", existingDoc.UID, existingDoc.Hash)
+	updated := *existingDoc
+	updated.UpdateCertificates(chainDoc.Certificates)
+	return &updated, nil
+}
+
+//newDocumentContent is chainDoc transformed into a brand new Document via
+//transformNewUnresolved, with its checksum256 content references left
+//unresolved. It lets a Batch fold those references into the single
+//combined hashUIDMap it resolves for the whole batch, instead of each new
+//document triggering its own GetHashUIDMap lookup the way transformNew does.
+type newDocumentContent struct {
+	doc              *Document
+	checksumContents []*Content
+}
+
+//transformNewUnresolved runs the same NewDocument/populateTypedValues steps
+//as transformNew, but leaves checksum256 content references unresolved for
+//the caller to fill in once it has a hash->UID map covering them.
+func (m *Doccache) transformNewUnresolved(chainDoc *ChainDocument) *newDocumentContent {
+	doc := NewDocument(chainDoc)
+	populateTypedValues(doc)
+	return &newDocumentContent{doc: doc, checksumContents: doc.GetChecksumContents()}
+}
+
+//prepareStoreWithPrebuilt resolves chainDoc against a pre-computed
+//hash->UID map: for an already-existing document it fetches the real,
+//complete Document (getExistingDocument) rather than fabricating one from
+//just the UID, so the cache never ends up holding a copy missing its
+//content groups; for a brand new document it reuses prebuilt (from
+//transformNewUnresolved) instead of calling transformNew, so its
+//checksum256 references resolve from hashUIDMap directly rather than a
+//second, per-document Dgraph lookup.
+func (m *Doccache) prepareStoreWithPrebuilt(chainDoc *ChainDocument, hashUIDMap map[string]string, prebuilt *newDocumentContent) (*Document, error) {
+	if _, ok := hashUIDMap[chainDoc.Hash]; ok {
+		existingDoc, err := m.getExistingDocument(chainDoc.Hash)
 		if err != nil {
-			return err
+			return nil, err
 		}
-	} else {
-		log.Printf("Updating certificates for document: <%v>%v", doc.UID, doc.Hash)
-		doc.UpdateCertificates(chainDoc.Certificates)
+		return m.applyStore(chainDoc, existingDoc)
 	}
-
-	_, err = m.dgraph.MutateJSON(doc, false)
-	return err
+	log.Printf("Creating document: %v", chainDoc.Hash)
+	resolveChecksumReferences(chainDoc.Hash, prebuilt.checksumContents, hashUIDMap)
+	return prebuilt.doc, nil
 }
 
 //DeleteDocument Deletes a document
@@ -241,7 +398,12 @@ func (m *Doccache) DeleteDocument(chainDoc *ChainDocument) error {
 	if uid != "" {
 		log.Printf("Deleting Node: <%v>%v", uid, chainDoc.Hash)
 		_, err = m.dgraph.DeleteNode(uid)
-		return err
+		if err != nil {
+			return err
+		}
+		m.cache.RemoveDocument(chainDoc.Hash)
+		m.notifyDocumentDeleted(chainDoc.Hash, uid)
+		return nil
 	}
 	log.Printf("Document: %v not found, couldn't delete", chainDoc.Hash)
 	return nil
@@ -267,27 +429,55 @@ func (m *Doccache) MutateEdge(chainEdge *ChainEdge, deleteOp bool) error {
 	}
 	log.Printf("Mutating [Edge: %v, From: <%v>%v, To: <%v>%v] Delete Op: %v", chainEdge.Name, fromUID, chainEdge.From, toUID, chainEdge.To, deleteOp)
 	_, err = m.dgraph.MutateEdge(fromUID, toUID, chainEdge.Name, deleteOp)
-	return err
-
+	if err != nil {
+		return err
+	}
+	m.cache.AddEdge(chainEdge.From, chainEdge.Name, deleteOp)
+	m.cache.InvalidateDocument(chainEdge.From)
+	m.notifyEdgeMutated(chainEdge, fromUID, toUID, deleteOp)
+	return nil
 }
 
 func (m *Doccache) updateDocumentTypeSchema(newField string) error {
-	if _, ok := m.documentFieldMap[newField]; !ok {
-		fields := ""
-		for key := range m.documentFieldMap {
-			fields += "\n" + key
-		}
-		err := m.dgraph.UpdateSchema(fmt.Sprintf(
-			`
-				%v: [uid] .
-				type Document{
-					%v
-					%v
-				}
-		 `, newField, fields, newField))
-		if err != nil {
-			return err
+	return m.updateDocumentTypeSchemas([]string{newField})
+}
+
+//updateDocumentTypeSchemas coalesces one or more new edge field names into
+//a single Document schema alter, instead of one alter per field. Used by
+//Batch.Commit so a block with several new edge names only pays for one
+//schema round trip.
+func (m *Doccache) updateDocumentTypeSchemas(newFields []string) error {
+	pending := make([]string, 0, len(newFields))
+	for _, newField := range newFields {
+		if _, ok := m.documentFieldMap[newField]; !ok {
+			pending = append(pending, newField)
 		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	fields := ""
+	for key := range m.documentFieldMap {
+		fields += "\n" + key
+	}
+	predicates := ""
+	newFieldDecls := ""
+	for _, newField := range pending {
+		predicates += fmt.Sprintf("%v: [uid] .\n", newField)
+		newFieldDecls += "\n" + newField
+	}
+	err := m.dgraph.UpdateSchema(fmt.Sprintf(
+		`
+			%v
+			type Document{
+				%v
+				%v
+			}
+	 `, predicates, fields, newFieldDecls))
+	if err != nil {
+		return err
+	}
+	for _, newField := range pending {
 		m.documentFieldMap[newField] = &dgraph.SchemaField{Name: newField}
 	}
 	return nil
@@ -295,17 +485,25 @@ func (m *Doccache) updateDocumentTypeSchema(newField string) error {
 
 func (m *Doccache) transformNew(chainDoc *ChainDocument) (*Document, error) {
 	doc := NewDocument(chainDoc)
+	populateTypedValues(doc)
 	checksumContents := doc.GetChecksumContents()
 	hashes := make([]string, 0, len(checksumContents))
 	for _, checksumContent := range checksumContents {
 		hashes = append(hashes, checksumContent.Value)
 	}
 	hashUIDMap, err := m.GetHashUIDMap(hashes)
-
 	if err != nil {
 		return nil, err
 	}
+	resolveChecksumReferences(chainDoc.Hash, checksumContents, hashUIDMap)
+	return doc, nil
+}
 
+//resolveChecksumReferences points each of doc's checksum256 contents at the
+//Document node hashUIDMap resolved it to, logging instead of failing when a
+//referenced hash isn't found (the chain can reference a document that
+//hasn't been stored yet).
+func resolveChecksumReferences(hash string, checksumContents []*Content, hashUIDMap map[string]string) {
 	for _, checksumContent := range checksumContents {
 		if uid, ok := hashUIDMap[checksumContent.Value]; ok {
 			checksumContent.Document = []*Document{
@@ -314,10 +512,9 @@ func (m *Doccache) transformNew(chainDoc *ChainDocument) (*Document, error) {
 				},
 			}
 		} else {
-			log.Printf("Document with hash: %v not found, referenced from document: %v", checksumContent.Value, chainDoc.Hash)
+			log.Printf("Document with hash: %v not found, referenced from document: %v", checksumContent.Value, hash)
 		}
 	}
-	return doc, nil
 }
 
 func configureRequest(rc *RequestConfig) string {
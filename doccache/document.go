@@ -0,0 +1,152 @@
+package doccache
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//Document mirrors the Document type stored in Dgraph: a chain document's
+//hash, creator metadata, its content groups and any certificates attached
+//to it.
+type Document struct {
+	UID           string          `json:"uid,omitempty"`
+	DType         []string        `json:"dgraph.type,omitempty"`
+	Hash          string          `json:"hash,omitempty"`
+	Creator       string          `json:"creator,omitempty"`
+	CreatedDate   string          `json:"created_date,omitempty"`
+	ContentGroups []*ContentGroup `json:"content_groups,omitempty"`
+	Certificates  []*Certificate  `json:"certificates,omitempty"`
+}
+
+//NewDocument transforms chainDoc - the payload callers hand to
+//StoreDocument/Batch.StoreDocument - into the Document shape stored in
+//Dgraph.
+func NewDocument(chainDoc *ChainDocument) *Document {
+	return &Document{
+		DType:         []string{"Document"},
+		Hash:          chainDoc.Hash,
+		Creator:       chainDoc.Creator,
+		CreatedDate:   chainDoc.CreatedDate,
+		ContentGroups: chainDoc.ContentGroups,
+		Certificates:  chainDoc.Certificates,
+	}
+}
+
+//GetType returns the document's "type" content value - the convention
+//EOSIO chain documents use to tag what kind of document they are (e.g.
+//"role", "assignment") - from its first content group that declares one.
+func (d *Document) GetType() string {
+	for _, group := range d.ContentGroups {
+		for _, content := range group.Contents {
+			if content.Label == "type" {
+				return content.Value
+			}
+		}
+	}
+	return ""
+}
+
+//GetChecksumContents returns every checksum256 content across d's content
+//groups: the hashes it references to other documents.
+func (d *Document) GetChecksumContents() []*Content {
+	var checksums []*Content
+	for _, group := range d.ContentGroups {
+		for _, content := range group.Contents {
+			if content.Type == contentTypeChecksum256 {
+				checksums = append(checksums, content)
+			}
+		}
+	}
+	return checksums
+}
+
+//UpdateCertificates replaces d's certificates with certs, refreshing an
+//already-stored document without touching its content groups.
+func (d *Document) UpdateCertificates(certs []*Certificate) {
+	d.Certificates = certs
+}
+
+//ContentGroup mirrors the ContentGroup type stored in Dgraph: an ordered
+//group of Content values.
+type ContentGroup struct {
+	UID                  string     `json:"uid,omitempty"`
+	DType                []string   `json:"dgraph.type,omitempty"`
+	ContentGroupSequence int        `json:"content_group_sequence"`
+	Contents             []*Content `json:"contents,omitempty"`
+}
+
+//Content mirrors the Content type stored in Dgraph: a single label/value
+//pair from a chain document, along with the typed predicates
+//populateTypedValues fills in for types that support range/full-text
+//queries (see content_value.go).
+type Content struct {
+	UID             string      `json:"uid,omitempty"`
+	DType           []string    `json:"dgraph.type,omitempty"`
+	ContentSequence int         `json:"content_sequence"`
+	Label           string      `json:"label"`
+	Value           string      `json:"value"`
+	Type            string      `json:"type"`
+	Document        []*Document `json:"document,omitempty"`
+
+	ValueInt         *int64     `json:"value_int,omitempty"`
+	ValueTime        *time.Time `json:"value_time,omitempty"`
+	ValueAssetAmount *float64   `json:"value_asset_amount,omitempty"`
+	ValueAssetSymbol string     `json:"value_asset_symbol,omitempty"`
+	ValueFulltext    string     `json:"value_fulltext,omitempty"`
+}
+
+//Certificate mirrors the Certificate type stored in Dgraph: an
+//attestation attached to a Document.
+type Certificate struct {
+	UID                   string   `json:"uid,omitempty"`
+	DType                 []string `json:"dgraph.type,omitempty"`
+	Certifier             string   `json:"certifier"`
+	Notes                 string   `json:"notes"`
+	CertificationDate     string   `json:"certification_date"`
+	CertificationSequence int      `json:"certification_sequence"`
+}
+
+//ToMap converts doc into the map[string]interface{} shape GetByHashAsMap
+//returns - keyed by its Dgraph predicate names, via a JSON round-trip over
+//doc's own struct tags - so a *Document fetched through the document cache
+//can be served from the same resolvers as a map fetched straight from
+//Dgraph.
+func ToMap(doc *Document) (map[string]interface{}, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+//Docs wraps the "docs" query root GetByHash/GetHashUIDMap/QueryContents
+//all query against.
+type Docs struct {
+	Docs []*Document `json:"docs"`
+}
+
+//ChainDocument is the payload chain-following callers hand to
+//Doccache.StoreDocument/DeleteDocument and Batch.StoreDocument/
+//DeleteDocument to upsert or remove a document.
+type ChainDocument struct {
+	Hash          string
+	Creator       string
+	CreatedDate   string
+	ContentGroups []*ContentGroup
+	Certificates  []*Certificate
+}
+
+//ChainEdge is a named relationship between two documents, identified by
+//hash, to create or delete via Doccache.MutateEdge/Batch.MutateEdge.
+type ChainEdge struct {
+	Name string
+	From string
+	To   string
+}
@@ -0,0 +1,43 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/sebastianmontero/hypha-document-cache-go/doccache"
+)
+
+//requestBody is the standard GraphQL-over-HTTP POST payload.
+type requestBody struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+//NewHandler builds an http.Handler serving a GraphQL API over dc,
+//mirroring Document/ContentGroup/Content/Certificate and answering
+//"document"/"documents" queries through dc's cache-backed lookups.
+func NewHandler(dc *doccache.Doccache) (http.Handler, error) {
+	schema, err := newSchema(&resolver{doccache: dc})
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body requestBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  body.Query,
+			OperationName:  body.OperationName,
+			VariableValues: body.Variables,
+			Context:        req.Context(),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}), nil
+}
@@ -0,0 +1,158 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"github.com/sebastianmontero/hypha-document-cache-go/doccache"
+)
+
+//resolver binds GraphQL field resolvers to a Doccache, so filters and
+//edge traversals can go through its cache-backed lookups instead of
+//always hitting Dgraph directly.
+type resolver struct {
+	doccache *doccache.Doccache
+}
+
+func (r *resolver) resolveDocument(p graphql.ResolveParams) (interface{}, error) {
+	hash, _ := p.Args["hash"].(string)
+	rc := requestConfigFor(p.Info)
+	return r.documentMap(hash, rc)
+}
+
+//documentMap answers hash/rc through GetByHash, so a query shaped to match
+//RequestConfig.isFull() (contentGroups and certificates both selected) is
+//served out of the document cache, then converts the result to the same
+//map[string]interface{} shape GetByHashAsMap returns. It must not be used
+//for a RequestConfig with Edges set: Document has no field for an
+//arbitrary, dynamically-named edge predicate, so resolveDocumentEdges
+//stays on GetByHashAsMap.
+func (r *resolver) documentMap(hash string, rc *doccache.RequestConfig) (map[string]interface{}, error) {
+	doc, err := r.doccache.GetByHash(hash, rc)
+	if err != nil {
+		return nil, err
+	}
+	return doccache.ToMap(doc)
+}
+
+func (r *resolver) resolveDocumentEdges(p graphql.ResolveParams) (interface{}, error) {
+	parent, ok := p.Source.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	hash, _ := parent["hash"].(string)
+	name, _ := p.Args["name"].(string)
+	rc := requestConfigFor(p.Info)
+	rc.Edges = []string{name}
+	doc, err := r.doccache.GetByHashAsMap(hash, rc)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, nil
+	}
+	return doc[name], nil
+}
+
+//resolveDocuments answers a filtered, cursor-paginated "documents" query.
+//The cursor is the same opaque offset doccache.ContentFilter.After/
+//doccache.EncodeCursor use, so it round-trips through GraphQL clients
+//unchanged. Matching hashes still go through documentMap, so a repeated
+//full-shaped query reuses the Doccache document cache.
+func (r *resolver) resolveDocuments(p graphql.ResolveParams) (interface{}, error) {
+	filter := doccache.ContentFilter{
+		Creator: stringArg(p.Args, "creator"),
+		Label:   stringArg(p.Args, "contentLabel"),
+		Value:   stringArg(p.Args, "contentValue"),
+		Type:    stringArg(p.Args, "contentType"),
+		After:   stringArg(p.Args, "after"),
+	}
+	if v, ok := p.Args["first"].(int); ok && v > 0 {
+		filter.First = v
+	}
+
+	hashes, hasNext, err := r.doccache.QueryContents(filter)
+	if err != nil {
+		return nil, err
+	}
+	offset := decodeOffset(filter.After)
+
+	rc := requestConfigFor(p.Info)
+	edges := make([]map[string]interface{}, 0, len(hashes))
+	for i, hash := range hashes {
+		doc, err := r.documentMap(hash, rc)
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+		edges = append(edges, map[string]interface{}{
+			"cursor": doccache.EncodeCursor(offset + i + 1),
+			"node":   doc,
+		})
+	}
+
+	var endCursor string
+	if len(edges) > 0 {
+		endCursor, _ = edges[len(edges)-1]["cursor"].(string)
+	}
+	return map[string]interface{}{
+		"edges": edges,
+		"pageInfo": map[string]interface{}{
+			"endCursor":   endCursor,
+			"hasNextPage": hasNext,
+		},
+	}, nil
+}
+
+func stringArg(args map[string]interface{}, name string) string {
+	v, _ := args[name].(string)
+	return v
+}
+
+//decodeOffset mirrors the cursor format doccache.EncodeCursor produces,
+//returning 0 for an empty or malformed cursor (treated as page one).
+func decodeOffset(cursor string) int {
+	if cursor == "" {
+		return 0
+	}
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0
+	}
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil {
+		return 0
+	}
+	return offset
+}
+
+//requestConfigFor inspects the requested selection set so a query only
+//fetches the contentGroups/certificates it actually asked for, avoiding
+//Dgraph over-fetching. It never sets Edges; a selected "edges" field is
+//fetched separately by resolveDocumentEdges.
+func requestConfigFor(info graphql.ResolveInfo) *doccache.RequestConfig {
+	rc := &doccache.RequestConfig{}
+	for _, field := range info.FieldASTs {
+		if field.SelectionSet == nil {
+			continue
+		}
+		for _, sel := range field.SelectionSet.Selections {
+			f, ok := sel.(*ast.Field)
+			if !ok {
+				continue
+			}
+			switch f.Name.Value {
+			case "contentGroups":
+				rc.ContentGroups = true
+			case "certificates":
+				rc.Certificates = true
+			}
+		}
+	}
+	return rc
+}
@@ -0,0 +1,127 @@
+//Package graphql exposes a Doccache as a GraphQL API mirroring the
+//Document/ContentGroup/Content/Certificate shape already used internally,
+//with resolvers that translate GraphQL selection sets into a
+//doccache.RequestConfig so callers only fetch what they asked for.
+package graphql
+
+import "github.com/graphql-go/graphql"
+
+//sourceField resolves a camelCase GraphQL field against the snake_case
+//Dgraph predicate name configureRequest actually queried for (e.g.
+//"createdDate" against the "created_date" key GetByHashAsMap returns).
+func sourceField(predicate string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		source, ok := p.Source.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		return source[predicate], nil
+	}
+}
+
+var certificateType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Certificate",
+	Fields: graphql.Fields{
+		"certifier":             &graphql.Field{Type: graphql.String},
+		"notes":                 &graphql.Field{Type: graphql.String},
+		"certificationDate":     &graphql.Field{Type: graphql.String, Resolve: sourceField("certification_date")},
+		"certificationSequence": &graphql.Field{Type: graphql.Int, Resolve: sourceField("certification_sequence")},
+	},
+})
+
+var contentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Content",
+	Fields: graphql.Fields{
+		"label":           &graphql.Field{Type: graphql.String},
+		"value":           &graphql.Field{Type: graphql.String},
+		"type":            &graphql.Field{Type: graphql.String},
+		"contentSequence": &graphql.Field{Type: graphql.Int, Resolve: sourceField("content_sequence")},
+	},
+})
+
+var contentGroupType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ContentGroup",
+	Fields: graphql.Fields{
+		"contentGroupSequence": &graphql.Field{Type: graphql.Int, Resolve: sourceField("content_group_sequence")},
+		"contents":             &graphql.Field{Type: graphql.NewList(contentType)},
+	},
+})
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"endCursor":   &graphql.Field{Type: graphql.String},
+		"hasNextPage": &graphql.Field{Type: graphql.Boolean},
+	},
+})
+
+//newDocumentType builds a fresh Document object type bound to res, whose
+//"edges" field resolver closes over that specific Doccache. It must not
+//be a package-level var: AddFieldConfig mutates the object's Fields map
+//in place, and a second newSchema call (a second Handler/Doccache in the
+//same process) would otherwise repoint the first schema's "edges" field
+//at the second resolver.
+func newDocumentType(res *resolver) *graphql.Object {
+	documentType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Document",
+		Fields: graphql.Fields{},
+	})
+	documentType.AddFieldConfig("hash", &graphql.Field{Type: graphql.String})
+	documentType.AddFieldConfig("creator", &graphql.Field{Type: graphql.String})
+	documentType.AddFieldConfig("createdDate", &graphql.Field{Type: graphql.String, Resolve: sourceField("created_date")})
+	documentType.AddFieldConfig("contentGroups", &graphql.Field{Type: graphql.NewList(contentGroupType), Resolve: sourceField("content_groups")})
+	documentType.AddFieldConfig("certificates", &graphql.Field{Type: graphql.NewList(certificateType)})
+	documentType.AddFieldConfig("edges", &graphql.Field{
+		Type: graphql.NewList(documentType),
+		Args: graphql.FieldConfigArgument{
+			"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+		Resolve: res.resolveDocumentEdges,
+	})
+	return documentType
+}
+
+//newSchema builds the GraphQL schema served by a Handler, with resolvers
+//bound to res.
+func newSchema(res *resolver) (graphql.Schema, error) {
+	documentType := newDocumentType(res)
+	documentEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DocumentEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.String},
+			"node":   &graphql.Field{Type: documentType},
+		},
+	})
+	documentConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DocumentConnection",
+		Fields: graphql.Fields{
+			"edges":    &graphql.Field{Type: graphql.NewList(documentEdgeType)},
+			"pageInfo": &graphql.Field{Type: pageInfoType},
+		},
+	})
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"document": &graphql.Field{
+				Type: documentType,
+				Args: graphql.FieldConfigArgument{
+					"hash": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: res.resolveDocument,
+			},
+			"documents": &graphql.Field{
+				Type: documentConnectionType,
+				Args: graphql.FieldConfigArgument{
+					"creator":      &graphql.ArgumentConfig{Type: graphql.String},
+					"contentLabel": &graphql.ArgumentConfig{Type: graphql.String},
+					"contentValue": &graphql.ArgumentConfig{Type: graphql.String},
+					"contentType":  &graphql.ArgumentConfig{Type: graphql.String},
+					"first":        &graphql.ArgumentConfig{Type: graphql.Int},
+					"after":        &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: res.resolveDocuments,
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
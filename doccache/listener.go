@@ -0,0 +1,37 @@
+package doccache
+
+//Listener is notified of successful mutations applied through a Doccache,
+//so downstream services can react to cache changes without polling
+//Dgraph. Implementations must return quickly; OnDocumentStored/
+//OnDocumentDeleted/OnEdgeMutated are called synchronously after the
+//triggering mutation has been committed.
+type Listener interface {
+	OnDocumentStored(doc *Document)
+	OnDocumentDeleted(hash, uid string)
+	OnEdgeMutated(edge *ChainEdge, fromUID, toUID string, deleted bool)
+}
+
+//Register adds listener to the set notified after StoreDocument,
+//DeleteDocument and MutateEdge succeed. Listeners are notified in the
+//order they were registered.
+func (m *Doccache) Register(listener Listener) {
+	m.listeners = append(m.listeners, listener)
+}
+
+func (m *Doccache) notifyDocumentStored(doc *Document) {
+	for _, listener := range m.listeners {
+		listener.OnDocumentStored(doc)
+	}
+}
+
+func (m *Doccache) notifyDocumentDeleted(hash, uid string) {
+	for _, listener := range m.listeners {
+		listener.OnDocumentDeleted(hash, uid)
+	}
+}
+
+func (m *Doccache) notifyEdgeMutated(edge *ChainEdge, fromUID, toUID string, deleted bool) {
+	for _, listener := range m.listeners {
+		listener.OnEdgeMutated(edge, fromUID, toUID, deleted)
+	}
+}
@@ -0,0 +1,45 @@
+//Package listener provides built-in doccache.Listener implementations
+//that turn Doccache mutations into real-time events: a publisher that
+//writes to a messaging bus (NATS or Kafka) and a webhook publisher that
+//POSTs to a configured list of URLs, plus a filtering wrapper that can
+//restrict either one to documents of interest.
+package listener
+
+import (
+	"encoding/json"
+
+	"github.com/sebastianmontero/hypha-document-cache-go/doccache"
+)
+
+//EventType identifies which Doccache mutation an Event describes.
+type EventType string
+
+const (
+	//EventDocumentStored fires after Doccache.StoreDocument creates or
+	//updates a document.
+	EventDocumentStored EventType = "document_stored"
+	//EventDocumentDeleted fires after Doccache.DeleteDocument removes a
+	//document.
+	EventDocumentDeleted EventType = "document_deleted"
+	//EventEdgeMutated fires after Doccache.MutateEdge creates or deletes
+	//an edge between two documents.
+	EventEdgeMutated EventType = "edge_mutated"
+)
+
+//Event is the JSON payload published for every Doccache mutation.
+type Event struct {
+	Type     EventType           `json:"type"`
+	Document *doccache.Document  `json:"document,omitempty"`
+	Hash     string              `json:"hash,omitempty"`
+	UID      string              `json:"uid,omitempty"`
+	Edge     *doccache.ChainEdge `json:"edge,omitempty"`
+	FromUID  string              `json:"fromUid,omitempty"`
+	ToUID    string              `json:"toUid,omitempty"`
+	Deleted  bool                `json:"deleted,omitempty"`
+}
+
+//Marshal serializes the Event as JSON, the wire format used by both the
+//publisher and webhook listeners.
+func (e *Event) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
@@ -0,0 +1,40 @@
+package listener
+
+import "github.com/sebastianmontero/hypha-document-cache-go/doccache"
+
+//Filter wraps a doccache.Listener so it only fires OnDocumentStored for
+//documents whose content type is in an allow-list. OnDocumentDeleted and
+//OnEdgeMutated are always forwarded, since by the time a document is
+//deleted its content type is no longer available to filter on.
+type Filter struct {
+	delegate doccache.Listener
+	allow    map[string]bool
+}
+
+//NewFilter wraps delegate so only documents whose "type" content matches
+//one of allowedTypes trigger OnDocumentStored.
+func NewFilter(delegate doccache.Listener, allowedTypes ...string) *Filter {
+	allow := make(map[string]bool, len(allowedTypes))
+	for _, t := range allowedTypes {
+		allow[t] = true
+	}
+	return &Filter{delegate: delegate, allow: allow}
+}
+
+//OnDocumentStored implements doccache.Listener.
+func (f *Filter) OnDocumentStored(doc *doccache.Document) {
+	if doc != nil && !f.allow[doc.GetType()] {
+		return
+	}
+	f.delegate.OnDocumentStored(doc)
+}
+
+//OnDocumentDeleted implements doccache.Listener.
+func (f *Filter) OnDocumentDeleted(hash, uid string) {
+	f.delegate.OnDocumentDeleted(hash, uid)
+}
+
+//OnEdgeMutated implements doccache.Listener.
+func (f *Filter) OnEdgeMutated(edge *doccache.ChainEdge, fromUID, toUID string, deleted bool) {
+	f.delegate.OnEdgeMutated(edge, fromUID, toUID, deleted)
+}
@@ -0,0 +1,96 @@
+package listener
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/sebastianmontero/hypha-document-cache-go/doccache"
+)
+
+//Publisher sends a payload to a named subject/topic on a messaging bus.
+//NATSConn and KafkaProducer below adapt the respective client libraries
+//to this interface.
+type Publisher interface {
+	Publish(subject string, payload []byte) error
+}
+
+//NATSConn is the subset of *nats.Conn a PublisherListener needs.
+type NATSConn interface {
+	Publish(subject string, data []byte) error
+}
+
+type natsPublisher struct {
+	conn NATSConn
+}
+
+//NewNATSPublisher adapts a NATS connection into a Publisher.
+func NewNATSPublisher(conn NATSConn) Publisher {
+	return &natsPublisher{conn: conn}
+}
+
+func (p *natsPublisher) Publish(subject string, payload []byte) error {
+	return p.conn.Publish(subject, payload)
+}
+
+//KafkaProducer is the subset of a Kafka producer client (e.g.
+//*kafka.Writer from segmentio/kafka-go) a PublisherListener needs.
+type KafkaProducer interface {
+	Publish(topic string, key, value []byte) error
+}
+
+type kafkaPublisher struct {
+	producer KafkaProducer
+}
+
+//NewKafkaPublisher adapts a Kafka producer into a Publisher.
+func NewKafkaPublisher(producer KafkaProducer) Publisher {
+	return &kafkaPublisher{producer: producer}
+}
+
+func (p *kafkaPublisher) Publish(subject string, payload []byte) error {
+	return p.producer.Publish(subject, nil, payload)
+}
+
+//PublisherListener is a doccache.Listener that publishes an Event to a
+//Publisher for every document/edge mutation, under the subject
+//"<prefix>.<EventType>".
+type PublisherListener struct {
+	publisher Publisher
+	prefix    string
+}
+
+//NewPublisherListener creates a PublisherListener that publishes under
+//subjects prefixed with prefix, e.g. "hypha.docs".
+func NewPublisherListener(publisher Publisher, prefix string) *PublisherListener {
+	return &PublisherListener{publisher: publisher, prefix: prefix}
+}
+
+func (l *PublisherListener) subject(eventType EventType) string {
+	return fmt.Sprintf("%v.%v", l.prefix, eventType)
+}
+
+func (l *PublisherListener) publish(event *Event) {
+	payload, err := event.Marshal()
+	if err != nil {
+		log.Printf("listener: failed marshalling event %v: %v", event.Type, err)
+		return
+	}
+	if err := l.publisher.Publish(l.subject(event.Type), payload); err != nil {
+		log.Printf("listener: failed publishing event %v: %v", event.Type, err)
+	}
+}
+
+//OnDocumentStored implements doccache.Listener.
+func (l *PublisherListener) OnDocumentStored(doc *doccache.Document) {
+	l.publish(&Event{Type: EventDocumentStored, Document: doc})
+}
+
+//OnDocumentDeleted implements doccache.Listener.
+func (l *PublisherListener) OnDocumentDeleted(hash, uid string) {
+	l.publish(&Event{Type: EventDocumentDeleted, Hash: hash, UID: uid})
+}
+
+//OnEdgeMutated implements doccache.Listener.
+func (l *PublisherListener) OnEdgeMutated(edge *doccache.ChainEdge, fromUID, toUID string, deleted bool) {
+	l.publish(&Event{Type: EventEdgeMutated, Edge: edge, FromUID: fromUID, ToUID: toUID, Deleted: deleted})
+}
@@ -0,0 +1,66 @@
+package listener
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/sebastianmontero/hypha-document-cache-go/doccache"
+)
+
+//defaultWebhookTimeout bounds how long a single webhook POST can take.
+//Listener callbacks run synchronously on the mutation path (see the
+//Listener doc comment), so a client with no timeout (http.DefaultClient)
+//lets one slow or hanging URL stall every document store/delete.
+const defaultWebhookTimeout = 10 * time.Second
+
+//WebhookListener is a doccache.Listener that POSTs every Event, as JSON,
+//to a configured list of URLs. Modelled after shoutrrr's URL-list
+//notification services: each mutation fans out to every configured URL
+//independently, and a failing URL does not stop the others from being
+//notified.
+type WebhookListener struct {
+	urls   []string
+	client *http.Client
+}
+
+//NewWebhookListener creates a WebhookListener that posts to urls with an
+//HTTP client bounded by defaultWebhookTimeout.
+func NewWebhookListener(urls ...string) *WebhookListener {
+	return &WebhookListener{urls: urls, client: &http.Client{Timeout: defaultWebhookTimeout}}
+}
+
+func (l *WebhookListener) post(event *Event) {
+	payload, err := event.Marshal()
+	if err != nil {
+		log.Printf("listener: failed marshalling event %v: %v", event.Type, err)
+		return
+	}
+	for _, url := range l.urls {
+		resp, err := l.client.Post(url, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			log.Printf("listener: failed posting event %v to %v: %v", event.Type, url, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("listener: webhook %v returned status %v for event %v", url, resp.StatusCode, event.Type)
+		}
+	}
+}
+
+//OnDocumentStored implements doccache.Listener.
+func (l *WebhookListener) OnDocumentStored(doc *doccache.Document) {
+	l.post(&Event{Type: EventDocumentStored, Document: doc})
+}
+
+//OnDocumentDeleted implements doccache.Listener.
+func (l *WebhookListener) OnDocumentDeleted(hash, uid string) {
+	l.post(&Event{Type: EventDocumentDeleted, Hash: hash, UID: uid})
+}
+
+//OnEdgeMutated implements doccache.Listener.
+func (l *WebhookListener) OnEdgeMutated(edge *doccache.ChainEdge, fromUID, toUID string, deleted bool) {
+	l.post(&Event{Type: EventEdgeMutated, Edge: edge, FromUID: fromUID, ToUID: toUID, Deleted: deleted})
+}
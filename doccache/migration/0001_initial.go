@@ -0,0 +1,75 @@
+package migration
+
+import "github.com/sebastianmontero/hypha-document-cache-go/dgraph"
+
+//baseSchema mirrors the schema constant doccache.PrepareSchema applies for
+//a brand new Dgraph instance: the Document/ContentGroup/Content/Certificate
+//types and their predicates.
+const baseSchema = `
+      type Document {
+          hash
+          created_date
+          creator
+          content_groups
+          certificates
+      }
+
+      type ContentGroup {
+        content_group_sequence
+        contents
+      }
+
+      type Content {
+        label
+        value
+        type
+        content_sequence
+        document
+      }
+
+      type Certificate {
+        certifier
+        notes
+        certification_date
+        certification_sequence
+      }
+
+      hash: string @index(exact) .
+      created_date: datetime .
+      creator: string @index(term) .
+      content_groups: [uid] .
+      certificates: [uid] .
+
+      content_group_sequence: int .
+      contents: [uid] .
+
+      label: string @index(term) .
+      value: string @index(term) .
+      type: string @index(term) .
+      content_sequence: int .
+      document: [uid] .
+
+      certifier: string @index(term) .
+      notes: string .
+      certification_date: datetime .
+      certification_sequence: int .
+    `
+
+//Initial is migration 0001: it lays down the base Document/ContentGroup/
+//Content/Certificate schema. It is a no-op against a Dgraph instance that
+//already has the types, so it is safe to run against databases that were
+//bootstrapped by the pre-migration doccache.PrepareSchema.
+var Initial = &Migration{
+	Version: 1,
+	Name:    "initial",
+	Up: func(dg *dgraph.Dgraph) error {
+		missing, err := dg.MissingTypes([]string{"Document", "ContentGroup", "Content", "Certificate"})
+		if err != nil {
+			return err
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		return dg.UpdateSchema(baseSchema)
+	},
+}
@@ -0,0 +1,18 @@
+package migration
+
+import "github.com/sebastianmontero/hypha-document-cache-go/dgraph"
+
+//ContentReverseEdges is migration 0002: it adds @reverse to
+//content_groups and contents so a Content node's owning Document can be
+//found by traversing ~contents then ~content_groups, which the graphql
+//package's "documents" query needs to filter by content label/value/type.
+var ContentReverseEdges = &Migration{
+	Version: 2,
+	Name:    "content_reverse_edges",
+	Up: func(dg *dgraph.Dgraph) error {
+		return dg.UpdateSchema(`
+			content_groups: [uid] @reverse .
+			contents: [uid] @reverse .
+		`)
+	},
+}
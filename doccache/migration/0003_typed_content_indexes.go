@@ -0,0 +1,34 @@
+package migration
+
+import "github.com/sebastianmontero/hypha-document-cache-go/dgraph"
+
+//TypedContentIndexes is migration 0003: it adds typed predicates
+//alongside Content.value so a chain content's parsed value can also be
+//indexed for range/full-text queries, instead of only the term index on
+//its string form.
+var TypedContentIndexes = &Migration{
+	Version: 3,
+	Name:    "typed_content_indexes",
+	Up: func(dg *dgraph.Dgraph) error {
+		return dg.UpdateSchema(`
+			value_int: int @index(int) .
+			value_time: datetime @index(hour) .
+			value_asset_amount: float @index(float) .
+			value_asset_symbol: string @index(exact) .
+			value_fulltext: string @index(fulltext) .
+
+			type Content {
+				label
+				value
+				type
+				content_sequence
+				document
+				value_int
+				value_time
+				value_asset_amount
+				value_asset_symbol
+				value_fulltext
+			}
+		`)
+	},
+}
@@ -0,0 +1,16 @@
+package migration
+
+//DefaultBuilder returns a Builder pre-loaded with the migrations this
+//package ships. Downstream users that need their own migrations (new
+//indexes, predicate type changes, backfills, ...) should start from this
+//and Register additional ones on top, e.g.:
+//
+//	migrator := migration.NewMigrator(dg, migration.DefaultBuilder().
+//		Register(myMigration).
+//		Build())
+func DefaultBuilder() *Builder {
+	return NewBuilder().
+		Register(Initial).
+		Register(ContentReverseEdges).
+		Register(TypedContentIndexes)
+}
@@ -0,0 +1,178 @@
+//Package migration provides a numbered, ordered schema migration
+//subsystem for the Dgraph schema managed by doccache.Doccache.
+//
+//Migrations are registered with Register, applied in ascending Version
+//order by a Migrator, and recorded in a SchemaVersion node so a restart
+//only applies what is pending.
+package migration
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sebastianmontero/hypha-document-cache-go/dgraph"
+)
+
+//schemaVersionType is the Dgraph type used to record the highest applied
+//migration version.
+const schemaVersionType = "SchemaVersion"
+
+const schemaVersionSchema = `
+      type SchemaVersion {
+          version
+      }
+
+      version: int .
+    `
+
+//Migration is a single, numbered schema change. Up is applied once, in
+//Version order, against the Dgraph instance backing a Doccache.
+type Migration struct {
+	Version     int
+	Name        string
+	Up          func(dg *dgraph.Dgraph) error
+}
+
+//Builder collects Migrations so downstream users can register their own
+//alongside (or instead of) the ones this package ships, without editing
+//a monolithic schema constant.
+type Builder struct {
+	migrations []*Migration
+}
+
+//NewBuilder creates an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+//Register adds a Migration to the builder. Registering two migrations
+//with the same Version panics, since that would make apply order
+//ambiguous.
+func (b *Builder) Register(m *Migration) *Builder {
+	for _, existing := range b.migrations {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("migration: version %v already registered (%v)", m.Version, existing.Name))
+		}
+	}
+	b.migrations = append(b.migrations, m)
+	return b
+}
+
+//Build returns the registered migrations sorted in ascending Version
+//order.
+func (b *Builder) Build() []*Migration {
+	migrations := make([]*Migration, len(b.migrations))
+	copy(migrations, b.migrations)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+//Migrator applies a Builder's Migrations to a Dgraph instance, tracking
+//the highest applied version in a SchemaVersion node.
+type Migrator struct {
+	dgraph     *dgraph.Dgraph
+	migrations []*Migration
+}
+
+//NewMigrator creates a Migrator for the given migrations, which should
+//normally come from Builder.Build.
+func NewMigrator(dg *dgraph.Dgraph, migrations []*Migration) *Migrator {
+	return &Migrator{dgraph: dg, migrations: migrations}
+}
+
+//Migrate applies every Migration with a Version greater than the
+//currently recorded SchemaVersion, in order. It refuses to run if the
+//recorded version is newer than the highest Version known to this
+//binary, since that would mean rolling a schema back.
+func (m *Migrator) Migrate() error {
+	current, err := m.currentVersion()
+	if err != nil {
+		return err
+	}
+	latestKnown := 0
+	for _, migration := range m.migrations {
+		if migration.Version > latestKnown {
+			latestKnown = migration.Version
+		}
+	}
+	if current > latestKnown {
+		return fmt.Errorf("migration: database schema version %v is newer than the highest version known to this binary (%v)", current, latestKnown)
+	}
+	for _, migration := range m.migrations {
+		if migration.Version <= current {
+			continue
+		}
+		if err := migration.Up(m.dgraph); err != nil {
+			return fmt.Errorf("migration: failed applying %v_%v: %v", migration.Version, migration.Name, err)
+		}
+		if err := m.setVersion(migration.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) currentVersion() (int, error) {
+	uid, version, err := m.versionNode()
+	if err != nil {
+		return 0, err
+	}
+	if uid == "" {
+		missing, err := m.dgraph.MissingTypes([]string{schemaVersionType})
+		if err != nil {
+			return 0, err
+		}
+		if len(missing) > 0 {
+			if err := m.dgraph.UpdateSchema(schemaVersionSchema); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return version, nil
+}
+
+func (m *Migrator) versionNode() (uid string, version int, err error) {
+	versions := &struct {
+		Versions []struct {
+			UID     string `json:"uid"`
+			Version int    `json:"version"`
+		} `json:"versions"`
+	}{}
+	err = m.dgraph.Query(`
+		{
+			versions(func: type(SchemaVersion)){
+				uid
+				version
+			}
+		}
+	`, nil, versions)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(versions.Versions) == 0 {
+		return "", 0, nil
+	}
+	return versions.Versions[0].UID, versions.Versions[0].Version, nil
+}
+
+func (m *Migrator) setVersion(version int) error {
+	uid, _, err := m.versionNode()
+	if err != nil {
+		return err
+	}
+	if uid == "" {
+		uid = "_:schemaVersion"
+	}
+	_, err = m.dgraph.MutateJSON(&struct {
+		UID     string   `json:"uid"`
+		DType   []string `json:"dgraph.type"`
+		Version int      `json:"version"`
+	}{
+		UID:     uid,
+		DType:   []string{schemaVersionType},
+		Version: version,
+	}, false)
+	return err
+}
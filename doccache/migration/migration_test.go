@@ -0,0 +1,40 @@
+package migration
+
+import "testing"
+
+func TestBuilderBuildOrdersByVersion(t *testing.T) {
+	m3 := &Migration{Version: 3, Name: "three"}
+	m1 := &Migration{Version: 1, Name: "one"}
+	m2 := &Migration{Version: 2, Name: "two"}
+
+	built := NewBuilder().Register(m3).Register(m1).Register(m2).Build()
+
+	if len(built) != 3 {
+		t.Fatalf("len(built) = %v, want 3", len(built))
+	}
+	for i, want := range []*Migration{m1, m2, m3} {
+		if built[i] != want {
+			t.Errorf("built[%v] = %v, want %v", i, built[i].Name, want.Name)
+		}
+	}
+}
+
+func TestBuilderRegisterDuplicateVersionPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected Register to panic on a duplicate version")
+		}
+	}()
+	NewBuilder().
+		Register(&Migration{Version: 1, Name: "one"}).
+		Register(&Migration{Version: 1, Name: "one-again"})
+}
+
+func TestDefaultBuilderBuildsInVersionOrder(t *testing.T) {
+	built := DefaultBuilder().Build()
+	for i := 1; i < len(built); i++ {
+		if built[i-1].Version >= built[i].Version {
+			t.Fatalf("migrations not strictly ascending at index %v: %v >= %v", i, built[i-1].Version, built[i].Version)
+		}
+	}
+}